@@ -0,0 +1,68 @@
+package tpm2
+
+import (
+	"fmt"
+
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// TPM2 command codes used to label the originating command in Format and in
+// log lines built from it. This is a small, hand-maintained subset of the
+// full command code table in Part 2 of the TPM 2.0 spec, covering commands
+// commonly seen in error messages.
+const (
+	cmdCreatePrimary      tpmutil.Command = 0x00000131
+	cmdNVWrite            tpmutil.Command = 0x00000137
+	cmdPCREvent           tpmutil.Command = 0x0000013C
+	cmdStartup            tpmutil.Command = 0x00000144
+	cmdActivateCredential tpmutil.Command = 0x00000147
+	cmdCertify            tpmutil.Command = 0x00000148
+	cmdNVRead             tpmutil.Command = 0x0000014E
+	cmdCreate             tpmutil.Command = 0x00000153
+	cmdImport             tpmutil.Command = 0x00000156
+	cmdLoad               tpmutil.Command = 0x00000157
+	cmdQuote              tpmutil.Command = 0x00000158
+	cmdSign               tpmutil.Command = 0x0000015D
+	cmdUnseal             tpmutil.Command = 0x0000015E
+	cmdFlushContext       tpmutil.Command = 0x00000165
+	cmdReadPublic         tpmutil.Command = 0x00000173
+	cmdStartAuthSession   tpmutil.Command = 0x00000176
+	cmdGetCapability      tpmutil.Command = 0x0000017A
+	cmdPolicyPCR          tpmutil.Command = 0x0000017F
+	cmdPCRExtend          tpmutil.Command = 0x00000182
+	cmdEvictControl       tpmutil.Command = 0x00000120
+	cmdClear              tpmutil.Command = 0x00000126
+)
+
+var cmdName = map[tpmutil.Command]string{
+	cmdCreatePrimary:      "TPM2_CreatePrimary",
+	cmdNVWrite:            "TPM2_NV_Write",
+	cmdPCREvent:           "TPM2_PCREvent",
+	cmdStartup:            "TPM2_Startup",
+	cmdActivateCredential: "TPM2_ActivateCredential",
+	cmdCertify:            "TPM2_Certify",
+	cmdNVRead:             "TPM2_NV_Read",
+	cmdCreate:             "TPM2_Create",
+	cmdImport:             "TPM2_Import",
+	cmdLoad:               "TPM2_Load",
+	cmdQuote:              "TPM2_Quote",
+	cmdSign:               "TPM2_Sign",
+	cmdUnseal:             "TPM2_Unseal",
+	cmdFlushContext:       "TPM2_FlushContext",
+	cmdReadPublic:         "TPM2_ReadPublic",
+	cmdStartAuthSession:   "TPM2_StartAuthSession",
+	cmdGetCapability:      "TPM2_GetCapability",
+	cmdPolicyPCR:          "TPM2_PolicyPCR",
+	cmdPCRExtend:          "TPM2_PCR_Extend",
+	cmdEvictControl:       "TPM2_EvictControl",
+	cmdClear:              "TPM2_Clear",
+}
+
+// commandName returns the symbolic name of cmd, or its raw hex value if it
+// is not in the (intentionally partial) table above.
+func commandName(cmd tpmutil.Command) string {
+	if name, ok := cmdName[cmd]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%08x", uint32(cmd))
+}
@@ -1,7 +1,9 @@
 package tpm2
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/google/go-tpm/tpmutil"
 )
@@ -243,6 +245,195 @@ const (
 	RCF         = 0x0F
 )
 
+// TPM 1.1/1.2 base error codes. These occupy bits 0:9 of a TPM 1.x response
+// code; bit 10 (tpm1Vendor) marks a vendor-specific code and bit 11
+// (tpm1NonFatal) marks the error as non-fatal.
+const (
+	tpm1AuthFail          uint16 = 0x01
+	tpm1BadIndex          uint16 = 0x02
+	tpm1BadParameter      uint16 = 0x03
+	tpm1AuditFailure      uint16 = 0x04
+	tpm1ClearDisabled     uint16 = 0x05
+	tpm1Deactivated       uint16 = 0x06
+	tpm1Disabled          uint16 = 0x07
+	tpm1DisabledCmd       uint16 = 0x08
+	tpm1Fail              uint16 = 0x09
+	tpm1BadOrdinal        uint16 = 0x0A
+	tpm1InstallDisabled   uint16 = 0x0B
+	tpm1InvalidKeyHandle  uint16 = 0x0C
+	tpm1KeyNotFound       uint16 = 0x0D
+	tpm1InappropriateEnc  uint16 = 0x0E
+	tpm1MigrateFail       uint16 = 0x0F
+	tpm1InvalidPCRInfo    uint16 = 0x10
+	tpm1NoSpace           uint16 = 0x11
+	tpm1NoSRK             uint16 = 0x12
+	tpm1NotSealedBlob     uint16 = 0x13
+	tpm1OwnerSet          uint16 = 0x14
+	tpm1Resources         uint16 = 0x15
+	tpm1ShortRandom       uint16 = 0x16
+	tpm1Size              uint16 = 0x17
+	tpm1WrongPCRVal       uint16 = 0x18
+	tpm1BadParamSize      uint16 = 0x19
+	tpm1ShaThread         uint16 = 0x1A
+	tpm1ShaError          uint16 = 0x1B
+	tpm1FailedSelfTest    uint16 = 0x1C
+	tpm1Auth2Fail         uint16 = 0x1D
+	tpm1BadTag            uint16 = 0x1E
+	tpm1IOError           uint16 = 0x1F
+	tpm1EncryptError      uint16 = 0x20
+	tpm1DecryptError      uint16 = 0x21
+	tpm1InvalidAuthHandle uint16 = 0x22
+	tpm1NoEndorsement     uint16 = 0x23
+	tpm1InvalidKeyUsage   uint16 = 0x24
+	tpm1WrongEntityType   uint16 = 0x25
+	tpm1InvalidPostInit   uint16 = 0x26
+	tpm1InappropriateSig  uint16 = 0x27
+	tpm1BadKeyProperty    uint16 = 0x28
+	tpm1BadMigration      uint16 = 0x29
+	tpm1BadScheme         uint16 = 0x2A
+	tpm1BadDataSize       uint16 = 0x2B
+	tpm1BadMode           uint16 = 0x2C
+	tpm1BadPresence       uint16 = 0x2D
+	tpm1BadVersion        uint16 = 0x2E
+	tpm1NoWrapTransport   uint16 = 0x2F
+	tpm1AuditFailUnsucc   uint16 = 0x30
+	tpm1AuditFailSucc     uint16 = 0x31
+	tpm1NotResetable      uint16 = 0x32
+	tpm1NotLocal          uint16 = 0x33
+	tpm1BadType           uint16 = 0x34
+	tpm1InvalidResource   uint16 = 0x35
+	tpm1NotFIPS           uint16 = 0x36
+	tpm1InvalidFamily     uint16 = 0x37
+	tpm1NoNVPermission    uint16 = 0x38
+	tpm1RequiresSign      uint16 = 0x39
+	tpm1KeyNotSupported   uint16 = 0x3A
+	tpm1AuthConflict      uint16 = 0x3B
+	tpm1AreaLocked        uint16 = 0x3C
+	tpm1BadLocality       uint16 = 0x3D
+	tpm1ReadOnly          uint16 = 0x3E
+	tpm1PerNoWrite        uint16 = 0x3F
+	tpm1FamilyCount       uint16 = 0x40
+)
+
+// tpm1Vendor and tpm1NonFatal are the bits a TPM 1.x response code uses,
+// alongside the 10-bit base code, to flag a vendor-specific error and a
+// non-fatal error respectively. See the TPM 1.2 Main Part 2 spec, section on
+// TPM_RESULT encoding.
+const (
+	tpm1Vendor   uint16 = 0x400
+	tpm1NonFatal uint16 = 0x800
+	tpm1BaseMask uint16 = 0x3ff
+)
+
+var tpm1Msg = map[uint16]string{
+	tpm1AuthFail:          "authentication failed",
+	tpm1BadIndex:          "the index to a PCR, DIR or other register is incorrect",
+	tpm1BadParameter:      "one or more parameter is bad",
+	tpm1AuditFailure:      "an operation completed successfully but the auditing of that operation failed",
+	tpm1ClearDisabled:     "the clear disable flag is set and all clear operations now require physical access",
+	tpm1Deactivated:       "the TPM is deactivated",
+	tpm1Disabled:          "the TPM is disabled",
+	tpm1DisabledCmd:       "the target command has been disabled",
+	tpm1Fail:              "the operation failed",
+	tpm1BadOrdinal:        "the ordinal was unknown or inconsistent",
+	tpm1InstallDisabled:   "the ability to install an owner is disabled",
+	tpm1InvalidKeyHandle:  "the key handle cannot be interpreted",
+	tpm1KeyNotFound:       "the key handle points to an invalid key",
+	tpm1InappropriateEnc:  "unacceptable encryption scheme",
+	tpm1MigrateFail:       "migration authorization failed",
+	tpm1InvalidPCRInfo:    "PCR information could not be interpreted",
+	tpm1NoSpace:           "no room to load key",
+	tpm1NoSRK:             "there is no SRK set",
+	tpm1NotSealedBlob:     "an encrypted blob is invalid or was not created by this TPM",
+	tpm1OwnerSet:          "there is already an owner",
+	tpm1Resources:         "the TPM has insufficient internal resources to perform the requested action",
+	tpm1ShortRandom:       "a random string was too short",
+	tpm1Size:              "the TPM does not have the space to perform the operation",
+	tpm1WrongPCRVal:       "the named PCR value does not match the current PCR value",
+	tpm1BadParamSize:      "the paramSize argument to the command has the incorrect value",
+	tpm1ShaThread:         "there is no existing SHA-1 thread",
+	tpm1ShaError:          "the calculation is unable to proceed because the existing SHA-1 thread has already encountered an error",
+	tpm1FailedSelfTest:    "self-test has failed and the TPM has shut down",
+	tpm1Auth2Fail:         "the authorization for the second key in a 2 key function failed",
+	tpm1BadTag:            "the tag value sent for a command is invalid",
+	tpm1IOError:           "an IO error occurred transmitting information to the TPM",
+	tpm1EncryptError:      "the encryption process had a problem",
+	tpm1DecryptError:      "the decryption process did not complete",
+	tpm1InvalidAuthHandle: "an invalid handle was used",
+	tpm1NoEndorsement:     "the TPM does not have an EK installed",
+	tpm1InvalidKeyUsage:   "the usage of a key is not allowed",
+	tpm1WrongEntityType:   "the submitted entity type is not allowed",
+	tpm1InvalidPostInit:   "the command was received in the wrong sequence relative to TPM_Init and a subsequent TPM_Startup",
+	tpm1InappropriateSig:  "signed data cannot include additional DER information",
+	tpm1BadKeyProperty:    "the key properties in TPM_KEY_PARMS are not supported by this TPM",
+	tpm1BadMigration:      "the migration properties of this key are incorrect",
+	tpm1BadScheme:         "the signature or encryption scheme for this key is incorrect or not permitted in this situation",
+	tpm1BadDataSize:       "the size of the data (or blob) parameter is bad or inconsistent with the referenced key",
+	tpm1BadMode:           "a mode parameter is bad, such as capArea or subCapArea for TPM_GetCapability, physicalPresence parameter for TPM_PhysicalPresence, or migrationType for TPM_CreateMigrationBlob",
+	tpm1BadPresence:       "either physicalPresence or physicalPresenceLock bits have the wrong value",
+	tpm1BadVersion:        "the TPM cannot perform this version of the capability",
+	tpm1NoWrapTransport:   "the TPM does not allow for wrapped transport sessions",
+	tpm1AuditFailUnsucc:   "TPM audit construction failed and the underlying command was returning a failure code also",
+	tpm1AuditFailSucc:     "TPM audit construction failed and the underlying command was returning success",
+	tpm1NotResetable:      "attempt to reset a PCR register that does not have the resettable attribute",
+	tpm1NotLocal:          "attempt to reset a PCR register that requires locality and the locality modifier not part of command transport",
+	tpm1BadType:           "make identity blob not properly typed",
+	tpm1InvalidResource:   "when saving context identified resource type does not match actual resource",
+	tpm1NotFIPS:           "the TPM is attempting to execute a command only available when in FIPS mode",
+	tpm1InvalidFamily:     "the command is attempting to use an invalid family ID",
+	tpm1NoNVPermission:    "the permission to manipulate the NV storage is not available",
+	tpm1RequiresSign:      "the operation requires a signed command",
+	tpm1KeyNotSupported:   "wrong operation to load an NV key",
+	tpm1AuthConflict:      "NV_LoadKey blob requires both owner and blob authorization",
+	tpm1AreaLocked:        "the NV area is locked and not writable",
+	tpm1BadLocality:       "the locality is incorrect for the attempted operation",
+	tpm1ReadOnly:          "the NV area is read only and can't be written to",
+	tpm1PerNoWrite:        "there is no protection on the write to the NV area",
+	tpm1FamilyCount:       "the family count value does not match",
+}
+
+// tpm1ToFmt1 maps the subset of TPM 1.x base codes that have a direct TPM
+// 2.0 format 1 equivalent, so TPM1Error can participate in the same
+// errors.Is sentinels (e.g. ErrAuthFail) as TPM 2.0 errors.
+var tpm1ToFmt1 = map[uint16]RCFmt1{
+	tpm1AuthFail:  RCAuthFail,
+	tpm1Auth2Fail: RCAuthFail,
+}
+
+// TPM1Error is returned for response codes from a TPM 1.1/1.2 device,
+// recognized by bits 7:8 of the code both being unset. Code is the raw
+// 16-bit response code, including the vendor-specific (0x400) and non-fatal
+// (0x800) bits if set.
+type TPM1Error struct {
+	Code uint16
+}
+
+func (e TPM1Error) Error() string {
+	msg := tpm1Msg[e.Code&tpm1BaseMask]
+	if msg == "" {
+		msg = unknownCode
+	}
+	var tags string
+	if e.Code&tpm1Vendor != 0 {
+		tags += ", vendor-specific"
+	}
+	if e.Code&tpm1NonFatal != 0 {
+		tags += ", non-fatal"
+	}
+	return fmt.Sprintf("TPM 1.2 error code 0x%x : %s%s", e.Code, msg, tags)
+}
+
+// Is reports whether target is a format 1 sentinel whose code is the TPM 2.0
+// equivalent of this error's TPM 1.x base code.
+func (e TPM1Error) Is(target error) bool {
+	s, ok := target.(fmt1Sentinel)
+	if !ok {
+		return false
+	}
+	eq, ok := tpm1ToFmt1[e.Code&tpm1BaseMask]
+	return ok && eq == s.code
+}
+
 const unknownCode = "unknown error code"
 
 // Error is returned for all Format 0 errors from the TPM. It is used for general
@@ -259,13 +450,29 @@ func (e Error) Error() string {
 	return fmt.Sprintf("error code 0x%x : %s", e.Code, msg)
 }
 
-// VendorError represents a vendor-specific error response. These types of responses
-// are not decoded and Code contains the complete response code.
+// VendorError represents a vendor-specific error response. Manufacturer is
+// the active TPM manufacturer ID at the time the error was decoded (see
+// RegisterVendor and SetActiveVendor), or 0 if it could not be determined.
 type VendorError struct {
-	Code uint32
+	Code         uint32
+	Manufacturer uint32
+}
+
+// Message returns the manufacturer-specific description of Code, or the
+// empty string if Manufacturer is unregistered or the code is not in its
+// table.
+func (e VendorError) Message() string {
+	table, ok := vendorTable(e.Manufacturer)
+	if !ok {
+		return ""
+	}
+	return table[e.Code]
 }
 
 func (e VendorError) Error() string {
+	if msg := e.Message(); msg != "" {
+		return fmt.Sprintf("vendor error code 0x%x : %s", e.Code, msg)
+	}
 	return fmt.Sprintf("vendor error code 0x%x", e.Code)
 }
 
@@ -296,6 +503,11 @@ func (e ParameterError) Error() string {
 	return fmt.Sprintf("parameter %d, error code 0x%x : %s", e.Parameter, e.Code, msg)
 }
 
+// Is reports whether target is a format 1 sentinel (such as ErrAuthFail)
+// carrying the same code, regardless of whether this error is associated
+// with a parameter, handle or session.
+func (e ParameterError) Is(target error) bool { return fmt1Is(e.Code, target) }
+
 // HandleError describes an error related to a handle, and the handle number.
 type HandleError struct {
 	Code   RCFmt1
@@ -310,6 +522,11 @@ func (e HandleError) Error() string {
 	return fmt.Sprintf("handle %d, error code 0x%x : %s", e.Handle, e.Code, msg)
 }
 
+// Is reports whether target is a format 1 sentinel (such as ErrAuthFail)
+// carrying the same code, regardless of whether this error is associated
+// with a parameter, handle or session.
+func (e HandleError) Is(target error) bool { return fmt1Is(e.Code, target) }
+
 // SessionError describes an error related to a session, and the session number.
 type SessionError struct {
 	Code    RCFmt1
@@ -324,6 +541,200 @@ func (e SessionError) Error() string {
 	return fmt.Sprintf("session %d, error code 0x%x : %s", e.Session, e.Code, msg)
 }
 
+// Is reports whether target is a format 1 sentinel (such as ErrAuthFail)
+// carrying the same code, regardless of whether this error is associated
+// with a parameter, handle or session.
+func (e SessionError) Is(target error) bool { return fmt1Is(e.Code, target) }
+
+// fmt1Sentinel is the error type behind the exported format 1 sentinel
+// values (e.g. ErrAuthFail) so that errors.Is matches a ParameterError,
+// HandleError or SessionError carrying the same code irrespective of which
+// of the three concrete types wraps it.
+type fmt1Sentinel struct{ code RCFmt1 }
+
+// fmt1Is is the shared Is logic for ParameterError, HandleError and
+// SessionError: target matches if it is a format 1 sentinel for the same
+// code.
+func fmt1Is(code RCFmt1, target error) bool {
+	s, ok := target.(fmt1Sentinel)
+	return ok && code == s.code
+}
+
+func (s fmt1Sentinel) Error() string {
+	msg := fmt1Msg[s.code]
+	if msg == "" {
+		msg = unknownCode
+	}
+	return msg
+}
+
+// ErrAuthFail is the sentinel for RCAuthFail: errors.Is(err, ErrAuthFail)
+// reports true for any ParameterError, HandleError or SessionError with
+// that code, regardless of which of the three formats it was reported as.
+var ErrAuthFail error = fmt1Sentinel{RCAuthFail}
+
+// warnSentinel is the error type behind the exported warning sentinel
+// values (e.g. WarnRetry) so that errors.Is matches a Warning carrying the
+// same code.
+type warnSentinel struct{ code RCWarn }
+
+func (s warnSentinel) Error() string {
+	msg := warnMsg[s.code]
+	if msg == "" {
+		msg = unknownCode
+	}
+	return msg
+}
+
+// Is reports whether target is a warning sentinel (such as WarnRetry)
+// carrying the same code.
+func (w Warning) Is(target error) bool {
+	s, ok := target.(warnSentinel)
+	return ok && w.Code == s.code
+}
+
+// Warning sentinels for errors.Is. ErrNVUnavailable is named with the Err
+// prefix, matching its common use outside of retry loops, even though it
+// wraps a warning code like the rest of this set.
+var (
+	WarnRetry        error = warnSentinel{RCRetry}
+	WarnYielded      error = warnSentinel{RCYielded}
+	WarnTesting      error = warnSentinel{RCTesting}
+	WarnNVRate       error = warnSentinel{RCNVRate}
+	WarnLockout      error = warnSentinel{RCLockout}
+	ErrNVUnavailable error = warnSentinel{RCNVUnavailable}
+)
+
+// transientWarnings are the warning codes that indicate the TPM made no
+// permanent progress on the command and that a caller may reissue it
+// unmodified, typically after a short backoff.
+var transientWarnings = []error{
+	WarnRetry,
+	WarnYielded,
+	WarnNVRate,
+	WarnTesting,
+	ErrNVUnavailable,
+	warnSentinel{RCSessionMemory},
+	warnSentinel{RCObjectMemory},
+	warnSentinel{RCMemory},
+}
+
+// IsRetryable reports whether err is one of the transient TPM warnings
+// (RCRetry, RCYielded, RCNVRate, RCTesting, RCNVUnavailable,
+// RCSessionMemory, RCObjectMemory or RCMemory) for which reissuing the same
+// command is expected to make progress.
+func IsRetryable(err error) bool {
+	for _, sentinel := range transientWarnings {
+		if errors.Is(err, sentinel) {
+			return true
+		}
+	}
+	return false
+}
+
+// TSS2 layer identifiers, encoded in bits 16:23 of a response code returned
+// by software above the TPM itself (resource managers, the TCTI, marshaling
+// helpers, etc). A layer of 0 means the code came straight from the TPM and
+// is decoded with the format 0/1 logic below.
+const (
+	LayerTPM     uint8 = 0x00
+	LayerFeature uint8 = 0x06
+	LayerESAPI   uint8 = 0x07
+	LayerSYS     uint8 = 0x08
+	LayerMU      uint8 = 0x09
+	LayerTCTI    uint8 = 0x0A
+	LayerRM      uint8 = 0x0B
+	LayerDriver  uint8 = 0x0C
+)
+
+// LayerError is returned for response codes whose layer identifier (bits
+// 16:23) is non-zero, i.e. codes generated by software above the TPM such as
+// a resource manager or TCTI rather than by the TPM itself.
+type LayerError struct {
+	Layer     uint8
+	LayerName string
+	Code      uint16
+}
+
+func (e LayerError) Error() string {
+	name := e.LayerName
+	if name == "" {
+		name = fmt.Sprintf("0x%02x", e.Layer)
+	}
+	msg := unknownCode
+	if dec, ok := layerDecoderFor(e.Layer); ok && dec.decode != nil {
+		if m := dec.decode(e.Code); m != "" {
+			msg = m
+		}
+	}
+	return fmt.Sprintf("%s layer error code 0x%x : %s", name, e.Code, msg)
+}
+
+type layerDecoder struct {
+	name   string
+	decode func(uint16) string
+}
+
+// layerMu guards layerRegistry: a resource manager, TCTI or similar shim
+// may register its layer from its own init() while this package's init()
+// is registering the built-in layers, and later reads it from
+// LayerError.Error() and DecodeResponseCode during concurrent decodes.
+var layerMu sync.RWMutex
+
+var layerRegistry = map[uint8]layerDecoder{}
+
+// RegisterLayer registers a name and, optionally, a decoder for a TSS2
+// layer identifier, so that DecodeResponseCode can produce a LayerError
+// with a human readable message for codes returned by that layer. Pass a
+// nil decoder if only the name is known; LayerError then reports
+// "unknown error code" for that layer's codes until a decoder is
+// registered. It is typically called from the init() of a package
+// implementing a resource manager, TCTI or similar shim that needs to
+// surface its own errors through the same error type as the rest of this
+// package.
+func RegisterLayer(id uint8, name string, decoder func(uint16) string) {
+	layerMu.Lock()
+	defer layerMu.Unlock()
+	layerRegistry[id] = layerDecoder{name: name, decode: decoder}
+}
+
+func layerDecoderFor(id uint8) (layerDecoder, bool) {
+	layerMu.RLock()
+	defer layerMu.RUnlock()
+	dec, ok := layerRegistry[id]
+	return dec, ok
+}
+
+func init() {
+	// No message tables exist yet for these layers, only their names; see
+	// RegisterLayer's doc comment for how to add one.
+	RegisterLayer(LayerFeature, "FEATURE", nil)
+	RegisterLayer(LayerESAPI, "ESAPI", nil)
+	RegisterLayer(LayerSYS, "SYS", nil)
+	RegisterLayer(LayerMU, "MU", nil)
+	RegisterLayer(LayerTCTI, "TCTI", nil)
+	RegisterLayer(LayerRM, "RM", nil)
+	RegisterLayer(LayerDriver, "DRIVER", nil)
+}
+
+// DecodeResponseCode decodes a raw 32-bit TSS2 response code, taking the
+// layer identifier in bits 16:23 into account. Codes from layer 0 (the TPM
+// itself) are decoded exactly as decodeResponse does; codes from any other
+// registered layer are returned as a LayerError so callers can still
+// errors.As into a well known type regardless of which part of the stack
+// produced the error.
+func DecodeResponseCode(code uint32) error {
+	layer := uint8((code >> 16) & 0xff)
+	if layer == LayerTPM {
+		return decodeResponse(tpmutil.ResponseCode(code))
+	}
+	name := ""
+	if dec, ok := layerDecoderFor(layer); ok {
+		name = dec.name
+	}
+	return LayerError{Layer: layer, LayerName: name, Code: uint16(code)}
+}
+
 // Decode a TPM2 response code and return the appropriate error. Logic
 // according to the "Response Code Evaluation" chart in Part 1 of the TPM 2.0
 // spec.
@@ -332,11 +743,11 @@ func decodeResponse(code tpmutil.ResponseCode) error {
 		return nil
 	}
 	if code&0x180 == 0 { // Bits 7:8 == 0 is a TPM1 error
-		return fmt.Errorf("response status 0x%x", code)
+		return TPM1Error{Code: uint16(code)}
 	}
 	if code&0x80 == 0 { // Bit 7 unset
 		if code&0x400 > 0 { // Bit 10 set, vendor specific code
-			return VendorError{uint32(code)}
+			return VendorError{Code: uint32(code), Manufacturer: activeVendor()}
 		}
 		if code&0x800 > 0 { // Bit 11 set, warning with code in bit 0:6
 			return Warning{RCWarn(code & 0x7f)}
@@ -0,0 +1,167 @@
+package tpm2
+
+import (
+	"fmt"
+
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Reverse maps from response code to the symbolic name of the Go constant
+// representing it, used by Symbol and Format to print both the numeric and
+// symbolic identity of a response code. Hand-maintained alongside fmt0Msg,
+// fmt1Msg and warnMsg above.
+var fmt0Name = map[RCFmt0]string{
+	RCInitialize:      "TPM_RC_INITIALIZE",
+	RCFailure:         "TPM_RC_FAILURE",
+	RCSequence:        "TPM_RC_SEQUENCE",
+	RCPrivate:         "TPM_RC_PRIVATE",
+	RCHMAC:            "TPM_RC_HMAC",
+	RCDisabled:        "TPM_RC_DISABLED",
+	RCExclusive:       "TPM_RC_EXCLUSIVE",
+	RCAuthType:        "TPM_RC_AUTH_TYPE",
+	RCAuthMissing:     "TPM_RC_AUTH_MISSING",
+	RCPolicy:          "TPM_RC_POLICY",
+	RCPCR:             "TPM_RC_PCR",
+	RCPCRChanged:      "TPM_RC_PCR_CHANGED",
+	RCUpgrade:         "TPM_RC_UPGRADE",
+	RCTooManyContexts: "TPM_RC_TOO_MANY_CONTEXTS",
+	RCAuthUnavailable: "TPM_RC_AUTH_UNAVAILABLE",
+	RCReboot:          "TPM_RC_REBOOT",
+	RCUnbalanced:      "TPM_RC_UNBALANCED",
+	RCCommandSize:     "TPM_RC_COMMAND_SIZE",
+	RCCommandCode:     "TPM_RC_COMMAND_CODE",
+	RCAuthSize:        "TPM_RC_AUTHSIZE",
+	RCAuthContext:     "TPM_RC_AUTH_CONTEXT",
+	RCNVRange:         "TPM_RC_NV_RANGE",
+	RCNVSize:          "TPM_RC_NV_SIZE",
+	RCNVLocked:        "TPM_RC_NV_LOCKED",
+	RCNVAuthorization: "TPM_RC_NV_AUTHORIZATION",
+	RCNVUninitialized: "TPM_RC_NV_UNINITIALIZED",
+	RCNVSpace:         "TPM_RC_NV_SPACE",
+	RCNVDefined:       "TPM_RC_NV_DEFINED",
+	RCBadContext:      "TPM_RC_BAD_CONTEXT",
+	RCCPHash:          "TPM_RC_CPHASH",
+	RCParent:          "TPM_RC_PARENT",
+	RCNeedsTest:       "TPM_RC_NEEDS_TEST",
+	RCNoResult:        "TPM_RC_NO_RESULT",
+	RCSensitive:       "TPM_RC_SENSITIVE",
+}
+
+var fmt1Name = map[RCFmt1]string{
+	RCAsymmetric:   "TPM_RC_ASYMMETRIC",
+	RCAttributes:   "TPM_RC_ATTRIBUTES",
+	RCHash:         "TPM_RC_HASH",
+	RCValue:        "TPM_RC_VALUE",
+	RCHierarchy:    "TPM_RC_HIERARCHY",
+	RCKeySize:      "TPM_RC_KEY_SIZE",
+	RCMGF:          "TPM_RC_MGF",
+	RCMode:         "TPM_RC_MODE",
+	RCType:         "TPM_RC_TYPE",
+	RCHandle:       "TPM_RC_HANDLE",
+	RCKDF:          "TPM_RC_KDF",
+	RCRange:        "TPM_RC_RANGE",
+	RCAuthFail:     "TPM_RC_AUTH_FAIL",
+	RCNonce:        "TPM_RC_NONCE",
+	RCPP:           "TPM_RC_PP",
+	RCScheme:       "TPM_RC_SCHEME",
+	RCSize:         "TPM_RC_SIZE",
+	RCSymmetric:    "TPM_RC_SYMMETRIC",
+	RCTag:          "TPM_RC_TAG",
+	RCSelector:     "TPM_RC_SELECTOR",
+	RCInsufficient: "TPM_RC_INSUFFICIENT",
+	RCSignature:    "TPM_RC_SIGNATURE",
+	RCKey:          "TPM_RC_KEY",
+	RCPolicyFail:   "TPM_RC_POLICY_FAIL",
+	RCIntegrity:    "TPM_RC_INTEGRITY",
+	RCTicket:       "TPM_RC_TICKET",
+	RCReservedBits: "TPM_RC_RESERVED_BITS",
+	RCBadAuth:      "TPM_RC_BAD_AUTH",
+	RCExpired:      "TPM_RC_EXPIRED",
+	RCPolicyCC:     "TPM_RC_POLICY_CC",
+	RCBinding:      "TPM_RC_BINDING",
+	RCCurve:        "TPM_RC_CURVE",
+	RCECCPoint:     "TPM_RC_ECC_POINT",
+}
+
+var warnName = map[RCWarn]string{
+	RCContextGap:     "TPM_RC_CONTEXT_GAP",
+	RCObjectMemory:   "TPM_RC_OBJECT_MEMORY",
+	RCSessionMemory:  "TPM_RC_SESSION_MEMORY",
+	RCMemory:         "TPM_RC_MEMORY",
+	RCSessionHandles: "TPM_RC_SESSION_HANDLES",
+	RCObjectHandles:  "TPM_RC_OBJECT_HANDLES",
+	RCLocality:       "TPM_RC_LOCALITY",
+	RCYielded:        "TPM_RC_YIELDED",
+	RCCanceled:       "TPM_RC_CANCELED",
+	RCTesting:        "TPM_RC_TESTING",
+	RCReferenceH0:    "TPM_RC_REFERENCE_H0",
+	RCReferenceH1:    "TPM_RC_REFERENCE_H1",
+	RCReferenceH2:    "TPM_RC_REFERENCE_H2",
+	RCReferenceH3:    "TPM_RC_REFERENCE_H3",
+	RCReferenceH4:    "TPM_RC_REFERENCE_H4",
+	RCReferenceH5:    "TPM_RC_REFERENCE_H5",
+	RCReferenceH6:    "TPM_RC_REFERENCE_H6",
+	RCReferenceS0:    "TPM_RC_REFERENCE_S0",
+	RCReferenceS1:    "TPM_RC_REFERENCE_S1",
+	RCReferenceS2:    "TPM_RC_REFERENCE_S2",
+	RCReferenceS3:    "TPM_RC_REFERENCE_S3",
+	RCReferenceS4:    "TPM_RC_REFERENCE_S4",
+	RCReferenceS5:    "TPM_RC_REFERENCE_S5",
+	RCReferenceS6:    "TPM_RC_REFERENCE_S6",
+	RCNVRate:         "TPM_RC_NV_RATE",
+	RCLockout:        "TPM_RC_LOCKOUT",
+	RCRetry:          "TPM_RC_RETRY",
+	RCNVUnavailable:  "TPM_RC_NV_UNAVAILABLE",
+}
+
+func symbolOrUnknown(name string) string {
+	if name == "" {
+		return "TPM_RC_UNKNOWN"
+	}
+	return name
+}
+
+// Symbol returns the name of the Go constant for this error's code, e.g.
+// "TPM_RC_INITIALIZE", for use in log lines that should carry both the
+// numeric and symbolic identity of a response code.
+func (e Error) Symbol() string { return symbolOrUnknown(fmt0Name[e.Code]) }
+
+// Symbol returns the name of the Go constant for this error's code, e.g.
+// "TPM_RC_VALUE".
+func (e ParameterError) Symbol() string { return symbolOrUnknown(fmt1Name[e.Code]) }
+
+// Symbol returns the name of the Go constant for this error's code, e.g.
+// "TPM_RC_HANDLE".
+func (e HandleError) Symbol() string { return symbolOrUnknown(fmt1Name[e.Code]) }
+
+// Symbol returns the name of the Go constant for this error's code, e.g.
+// "TPM_RC_VALUE".
+func (e SessionError) Symbol() string { return symbolOrUnknown(fmt1Name[e.Code]) }
+
+// Format decodes code, the response to cmd, into a string modeled on
+// tpm2-tss's Tss2_RC_Decode: it carries the human-readable message, the
+// numeric response code, the symbolic constant name (annotated with the
+// offending parameter, handle or session number for format 1 errors), and
+// the command that produced it, e.g.
+//
+//	tpm:parameter(2):value is out of range or is not correct for the context (rc=0x000001c4, TPM_RC_VALUE|RC_P|0x2, cmd=TPM2_CreatePrimary)
+func Format(code tpmutil.ResponseCode, cmd tpmutil.Command) string {
+	cmdStr := commandName(cmd)
+	if code == tpmutil.RCSuccess {
+		return fmt.Sprintf("tpm:success (rc=0x%08x, cmd=%s)", uint32(code), cmdStr)
+	}
+	switch e := decodeResponse(code).(type) {
+	case Error:
+		return fmt.Sprintf("tpm:%s (rc=0x%08x, %s, cmd=%s)", fmt0Msg[e.Code], uint32(code), e.Symbol(), cmdStr)
+	case ParameterError:
+		return fmt.Sprintf("tpm:parameter(%d):%s (rc=0x%08x, %s|RC_P|0x%x, cmd=%s)", e.Parameter, fmt1Msg[e.Code], uint32(code), e.Symbol(), e.Parameter, cmdStr)
+	case HandleError:
+		return fmt.Sprintf("tpm:handle(%d):%s (rc=0x%08x, %s|RC_H|0x%x, cmd=%s)", e.Handle, fmt1Msg[e.Code], uint32(code), e.Symbol(), e.Handle, cmdStr)
+	case SessionError:
+		return fmt.Sprintf("tpm:session(%d):%s (rc=0x%08x, %s|RC_S|0x%x, cmd=%s)", e.Session, fmt1Msg[e.Code], uint32(code), e.Symbol(), e.Session, cmdStr)
+	case Warning:
+		return fmt.Sprintf("tpm:warn:%s (rc=0x%08x, %s, cmd=%s)", warnMsg[e.Code], uint32(code), symbolOrUnknown(warnName[e.Code]), cmdStr)
+	default:
+		return fmt.Sprintf("%s (rc=0x%08x, cmd=%s)", e.Error(), uint32(code), cmdStr)
+	}
+}
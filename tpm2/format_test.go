@@ -0,0 +1,166 @@
+package tpm2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-tpm/tpmutil"
+)
+
+func TestDecodeResponseBitMasking(t *testing.T) {
+	tests := []struct {
+		name string
+		code tpmutil.ResponseCode
+		want interface{}
+	}{
+		{"success", tpmutil.RCSuccess, nil},
+		{"tpm1", 0x01, TPM1Error{Code: 0x01}},
+		{"fmt0 error", 0x101, Error{Code: RCFailure}},
+		{"warning", 0x922, Warning{Code: RCRetry}},
+		{"vendor", 0x50a, VendorError{Code: 0x50a}},
+		{"parameter", 0x1c4, ParameterError{Code: RCValue, Parameter: RC1}},
+		{"handle", 0x18b, HandleError{Code: RCHandle, Handle: RC1}},
+		{"session", 0x98b, SessionError{Code: RCHandle, Session: RC1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := decodeResponse(tc.code)
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("decodeResponse(0x%x) = %#v, want nil", tc.code, got)
+				}
+				return
+			}
+			// VendorError carries whatever manufacturer happens to be
+			// active; only Code is pinned here (see vendor_test.go for
+			// manufacturer-specific coverage).
+			if ve, ok := tc.want.(VendorError); ok {
+				gotVE, ok := got.(VendorError)
+				if !ok || gotVE.Code != ve.Code {
+					t.Fatalf("decodeResponse(0x%x) = %#v, want VendorError{Code: 0x%x}", tc.code, got, ve.Code)
+				}
+				return
+			}
+			if got != tc.want {
+				t.Fatalf("decodeResponse(0x%x) = %#v, want %#v", tc.code, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeResponseCode(t *testing.T) {
+	// Layer 0 (the TPM itself) defers to decodeResponse.
+	if got, want := DecodeResponseCode(0x101), (Error{Code: RCFailure}); got != want {
+		t.Errorf("DecodeResponseCode(0x101) = %#v, want %#v", got, want)
+	}
+
+	// A registered layer with only a name (no decoder) is still reported
+	// with its symbolic name, falling back to "unknown error code".
+	code := uint32(LayerESAPI)<<16 | 0x05
+	got, ok := DecodeResponseCode(code).(LayerError)
+	if !ok {
+		t.Fatalf("DecodeResponseCode(0x%x) did not return a LayerError", code)
+	}
+	want := LayerError{Layer: LayerESAPI, LayerName: "ESAPI", Code: 0x05}
+	if got != want {
+		t.Errorf("DecodeResponseCode(0x%x) = %#v, want %#v", code, got, want)
+	}
+	if !strings.Contains(got.Error(), unknownCode) {
+		t.Errorf("LayerError.Error() = %q, want it to contain %q (no decoder registered yet)", got.Error(), unknownCode)
+	}
+
+	// An unregistered layer still decodes, with its raw ID as the name.
+	code = uint32(0x42)<<16 | 0x01
+	got, ok = DecodeResponseCode(code).(LayerError)
+	if !ok {
+		t.Fatalf("DecodeResponseCode(0x%x) did not return a LayerError", code)
+	}
+	if got.LayerName != "" {
+		t.Errorf("DecodeResponseCode(0x%x).LayerName = %q, want empty for an unregistered layer", code, got.LayerName)
+	}
+	if !strings.Contains(got.Error(), "0x42") {
+		t.Errorf("LayerError.Error() = %q, want it to fall back to the raw layer ID", got.Error())
+	}
+}
+
+func TestRegisterLayerDecoder(t *testing.T) {
+	const testLayer = 0xF0
+	RegisterLayer(testLayer, "TEST", func(code uint16) string {
+		if code == 0x01 {
+			return "test layer code one"
+		}
+		return ""
+	})
+
+	got := LayerError{Layer: testLayer, LayerName: "TEST", Code: 0x01}.Error()
+	if !strings.Contains(got, "test layer code one") {
+		t.Errorf("LayerError.Error() = %q, want it to use the registered decoder", got)
+	}
+
+	got = LayerError{Layer: testLayer, LayerName: "TEST", Code: 0x02}.Error()
+	if !strings.Contains(got, unknownCode) {
+		t.Errorf("LayerError.Error() = %q, want %q for a code the decoder doesn't recognize", got, unknownCode)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		code tpmutil.ResponseCode
+		cmd  tpmutil.Command
+		want []string // substrings that must appear in the output
+	}{
+		{
+			name: "success",
+			code: tpmutil.RCSuccess,
+			cmd:  cmdCreatePrimary,
+			want: []string{"success", "cmd=TPM2_CreatePrimary"},
+		},
+		{
+			name: "parameter error",
+			code: 0x1c4,
+			cmd:  cmdCreatePrimary,
+			want: []string{"tpm:parameter(1)", "TPM_RC_VALUE", "RC_P", "rc=0x000001c4", "cmd=TPM2_CreatePrimary"},
+		},
+		{
+			name: "handle error",
+			code: 0x18b,
+			cmd:  cmdLoad,
+			want: []string{"tpm:handle(1)", "TPM_RC_HANDLE", "RC_H", "cmd=TPM2_Load"},
+		},
+		{
+			name: "session error",
+			code: 0x98b,
+			cmd:  cmdStartAuthSession,
+			want: []string{"tpm:session(1)", "TPM_RC_HANDLE", "RC_S", "cmd=TPM2_StartAuthSession"},
+		},
+		{
+			name: "format 0 error",
+			code: 0x101,
+			cmd:  cmdClear,
+			want: []string{"TPM_RC_FAILURE", "cmd=TPM2_Clear"},
+		},
+		{
+			name: "warning",
+			code: 0x922,
+			cmd:  cmdGetCapability,
+			want: []string{"tpm:warn:", "TPM_RC_RETRY", "cmd=TPM2_GetCapability"},
+		},
+		{
+			name: "unknown command",
+			code: 0x101,
+			cmd:  0xdeadbeef,
+			want: []string{"cmd=0xdeadbeef"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Format(tc.code, tc.cmd)
+			for _, want := range tc.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Format(0x%x, 0x%x) = %q, want it to contain %q", tc.code, tc.cmd, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package tpm2
+
+import "sync"
+
+// Well known TPM manufacturer IDs, as reported by TPM_PT_MANUFACTURER: four
+// ASCII characters packed big-endian into a uint32. These are the IDs
+// RegisterVendor's built-in tables below are keyed on.
+const (
+	VendorIFX uint32 = 0x49465820 // "IFX "
+	VendorSTM uint32 = 0x53544D20 // "STM "
+	VendorNTC uint32 = 0x4E544320 // "NTC "
+	VendorIBM uint32 = 0x49424D20 // "IBM "
+)
+
+// vendorMu guards vendorRegistry, explicitVendor and vendorKnown: a
+// resource manager or similar shim may decode responses from multiple
+// goroutines while registering vendor tables or resolving the active
+// manufacturer.
+var vendorMu sync.RWMutex
+
+// vendorRegistry maps a TPM manufacturer ID to a table of that vendor's
+// response codes, populated by RegisterVendor.
+var vendorRegistry = map[uint32]map[uint32]string{}
+
+// RegisterVendor registers a table of vendor-specific response codes for
+// tpmManufacturer, the 4-character ASCII manufacturer ID reported by
+// TPM_PT_MANUFACTURER (see VendorIFX and friends). A later call for the
+// same manufacturer replaces the earlier table. Registering a table makes
+// VendorError.Message and VendorError.Error produce a symbolic name instead
+// of just the raw code, for any VendorError decoded while that
+// manufacturer is active (see SetActiveVendor).
+func RegisterVendor(tpmManufacturer uint32, table map[uint32]string) {
+	vendorMu.Lock()
+	defer vendorMu.Unlock()
+	vendorRegistry[tpmManufacturer] = table
+}
+
+func vendorTable(manufacturer uint32) (map[uint32]string, bool) {
+	vendorMu.RLock()
+	defer vendorMu.RUnlock()
+	table, ok := vendorRegistry[manufacturer]
+	return table, ok
+}
+
+func init() {
+	RegisterVendor(VendorIFX, ifxVendorTable)
+	RegisterVendor(VendorSTM, stmVendorTable)
+	RegisterVendor(VendorNTC, ntcVendorTable)
+	RegisterVendor(VendorIBM, ibmVendorTable)
+}
+
+// explicitVendor and vendorKnown hold the manufacturer set via
+// SetActiveVendor, taking precedence over ManufacturerProvider.
+var (
+	explicitVendor uint32
+	vendorKnown    bool
+)
+
+// SetActiveVendor tells this package which TPM manufacturer is in use, so
+// that decodeResponse can attach it to any VendorError it returns. Call
+// this once after connecting to a TPM whose manufacturer is already known.
+// It takes precedence over ManufacturerProvider.
+func SetActiveVendor(tpmManufacturer uint32) {
+	vendorMu.Lock()
+	defer vendorMu.Unlock()
+	explicitVendor = tpmManufacturer
+	vendorKnown = true
+}
+
+// manufacturerProvider is used to lazily determine the active TPM
+// manufacturer the first time a vendor-specific response code is decoded
+// and SetActiveVendor has not been called. Set it with
+// SetManufacturerProvider, not by assignment, so that reads of it in
+// activeVendor stay safe for concurrent use.
+var manufacturerProvider func() (uint32, error)
+
+// SetManufacturerProvider registers a closure used to lazily determine the
+// active TPM manufacturer the first time a vendor-specific response code is
+// decoded and SetActiveVendor has not been called. It is typically set to a
+// closure that issues TPM2_GetCapability for TPM_PT_MANUFACTURER over the
+// caller's open connection. The result is cached for the lifetime of the
+// process, the same way SetActiveVendor's value is.
+func SetManufacturerProvider(provider func() (uint32, error)) {
+	vendorMu.Lock()
+	defer vendorMu.Unlock()
+	manufacturerProvider = provider
+}
+
+// activeVendor returns the manufacturer ID to attach to a VendorError,
+// resolving and caching it from the registered ManufacturerProvider on
+// first use if SetActiveVendor has not been called. It returns 0 if the
+// manufacturer is unknown.
+func activeVendor() uint32 {
+	vendorMu.RLock()
+	known, explicit, provider := vendorKnown, explicitVendor, manufacturerProvider
+	vendorMu.RUnlock()
+	if known {
+		return explicit
+	}
+	if provider == nil {
+		return 0
+	}
+	m, err := provider()
+	if err != nil {
+		return 0
+	}
+	vendorMu.Lock()
+	explicitVendor, vendorKnown = m, true
+	vendorMu.Unlock()
+	return m
+}
+
+// ifxVendorTable covers a handful of Infineon (e.g. SLB9670) firmware
+// update and lockout codes. It is a starting point, not exhaustive; extend
+// it with RegisterVendor(VendorIFX, moreCodes) as more are catalogued.
+//
+// Keys must have bit 7 clear, bit 10 (0x400) set, and at least one of bits
+// 7:8 set (so decodeResponse doesn't mistake the code for a TPM 1.x
+// response) — the bit pattern decodeResponse uses to recognize a
+// vendor-specific code; see TestVendorTablesDecodeAsVendorError.
+var ifxVendorTable = map[uint32]string{
+	0x50a: "TPM_RC_IFX_FIELDUPGRADE_INVALIDMANIFEST",
+	0x50b: "TPM_RC_IFX_FIELDUPGRADE_WRONGTPMINFO",
+	0x50c: "TPM_RC_IFX_LOCKOUT_TIMEOUT",
+}
+
+// stmVendorTable is a starting point for STMicroelectronics response codes.
+var stmVendorTable = map[uint32]string{
+	0xF00: "TPM_RC_STM_SELFTEST_FAILURE",
+}
+
+// ntcVendorTable is a starting point for Nuvoton response codes.
+var ntcVendorTable = map[uint32]string{
+	0x50e: "TPM_RC_NTC_FIPS_FAILURE",
+}
+
+// ibmVendorTable is a starting point for the IBM software TPM (swtpm)
+// response codes.
+var ibmVendorTable = map[uint32]string{
+	0xD00: "TPM_RC_IBM_SW_TPM_FAILURE",
+}
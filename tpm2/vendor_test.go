@@ -0,0 +1,52 @@
+package tpm2
+
+import (
+	"testing"
+
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// TestVendorTablesDecodeAsVendorError catches the class of bug where a
+// registered vendor code doesn't actually have the bit pattern
+// decodeResponse uses to recognize a vendor-specific code (bit 7 clear, bit
+// 10 set): such a code would silently decode as a Warning or format 1
+// error instead, making its table entry dead code.
+func TestVendorTablesDecodeAsVendorError(t *testing.T) {
+	for manufacturer, table := range vendorRegistry {
+		for code, name := range table {
+			t.Run(name, func(t *testing.T) {
+				SetActiveVendor(manufacturer)
+				defer func() { vendorKnown = false }()
+
+				err := decodeResponse(tpmutil.ResponseCode(code))
+				ve, ok := err.(VendorError)
+				if !ok {
+					t.Fatalf("decodeResponse(0x%x) = %#v (%T), want VendorError", code, err, err)
+				}
+				if ve.Code != code {
+					t.Errorf("VendorError.Code = 0x%x, want 0x%x", ve.Code, code)
+				}
+				if ve.Manufacturer != manufacturer {
+					t.Errorf("VendorError.Manufacturer = 0x%x, want 0x%x", ve.Manufacturer, manufacturer)
+				}
+				if msg := ve.Message(); msg != name {
+					t.Errorf("VendorError.Message() = %q, want %q", msg, name)
+				}
+			})
+		}
+	}
+}
+
+func TestActiveVendorPrefersExplicitOverProvider(t *testing.T) {
+	defer func() {
+		vendorKnown = false
+		manufacturerProvider = nil
+	}()
+
+	SetManufacturerProvider(func() (uint32, error) { return VendorSTM, nil })
+	SetActiveVendor(VendorIFX)
+
+	if got := activeVendor(); got != VendorIFX {
+		t.Errorf("activeVendor() = 0x%x, want VendorIFX (explicit should win)", got)
+	}
+}
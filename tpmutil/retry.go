@@ -0,0 +1,28 @@
+package tpmutil
+
+import (
+	"context"
+	"time"
+)
+
+// RetryOnTransient re-issues fn against the TPM, backing off between
+// attempts, for as long as fn's error is classified as transient by
+// isTransient. It returns as soon as fn succeeds, returns a non-transient
+// error, or ctx is done.
+//
+// Most callers will pass tpm2.IsRetryable as isTransient; it is taken as a
+// parameter here, rather than imported directly, to avoid an import cycle
+// between this package and tpm2.
+func RetryOnTransient(ctx context.Context, backoff func(attempt int) time.Duration, isTransient func(error) bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isTransient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff(attempt)):
+		}
+	}
+}